@@ -3,141 +3,323 @@ package collectorsimulator
 import (
 	"context"
 	"fmt"
-	"os"
+	"net"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/confmap/provider/httpprovider"
+	"go.opentelemetry.io/collector/confmap/provider/httpsprovider"
 	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
 	"go.opentelemetry.io/collector/connector"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/extension"
 	"go.opentelemetry.io/collector/otelcol"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/processor"
 	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"go.signoz.io/signoz/pkg/query-service/collectorsimulator/inmemoryexporter"
 	"go.signoz.io/signoz/pkg/query-service/collectorsimulator/inmemoryreceiver"
 	"go.signoz.io/signoz/pkg/query-service/model"
 )
 
-// Puts together a collector service with inmemory receiver and exporter
-// for simulating processing of signal data through an otel collector
+// PipelineConfig describes one named pipeline to be wired up in the
+// simulated collector: the signal type it carries, the ids of the
+// receivers/connectors feeding it, the processor chain to run the
+// signal data through, and the ids of the exporters/connectors it
+// fans out to.
+//
+// Receiver/Exporter ids that don't match any ConnectorConfig.Id are
+// assumed to refer to inmemory receiver/exporter instances and are
+// created automatically, so tests can push/pull data on them via
+// GetReceiverByID/GetExporterByID. Ids that do match a connector are
+// wired to that connector instead, allowing pipelines to fan-in/fan-out
+// through it (eg: a routing or spanmetrics connector consuming a
+// "traces" pipeline and producing a "metrics" pipeline).
+type PipelineConfig struct {
+	Name       string
+	SignalType component.DataType
+
+	Receivers  []string
+	Processors []ProcessorConfig
+	Exporters  []string
+}
+
+// ConnectorConfig describes a connector component wired between 2 or more
+// of the pipelines above, identified by the same id used in
+// PipelineConfig.Receivers/Exporters.
+type ConnectorConfig struct {
+	Id     string
+	Type   component.Type
+	Config interface{}
+}
+
+// ExtensionConfig describes an extension enabled for the simulation (eg:
+// an auth extension a processor/connector depends on, or a storage
+// extension backing a persistent queue). Config may itself contain
+// confmap expansion refs (`${env:...}`, `${file:...}`, ...) that will be
+// resolved using the same providers as ProcessorConfig.Config.
+type ExtensionConfig struct {
+	Id     string
+	Type   component.Type
+	Config interface{}
+}
+
+// SimulationLogEntry is a single log entry emitted by the simulated
+// collector, captured in memory instead of being scraped off a log file.
+type SimulationLogEntry struct {
+	Timestamp time.Time
+	Level     zapcore.Level
+	Component string
+	Message   string
+	Fields    map[string]interface{}
+}
+
+// componentNameKeys are, in order of preference, the zap field keys the
+// collector logs a component's id under.
+var componentNameKeys = []string{"component", "kind", "name"}
+
+// componentNameFromFields extracts a log entry's component id from its
+// structured fields, trying each known key in a fixed order so the result
+// doesn't depend on Go's randomized map iteration order.
+func componentNameFromFields(fields map[string]interface{}) string {
+	for _, key := range componentNameKeys {
+		if v, ok := fields[key]; ok {
+			if s, ok := v.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// FilterLogsByMinLevel returns the entries from logs at or above minLevel,
+// preserving order. eg: pass zapcore.WarnLevel to surface only warnings
+// and fatal errors, dropping informational/debug noise.
+func FilterLogsByMinLevel(logs []SimulationLogEntry, minLevel zapcore.Level) []SimulationLogEntry {
+	filtered := []SimulationLogEntry{}
+	for _, logEntry := range logs {
+		if logEntry.Level >= minLevel {
+			filtered = append(filtered, logEntry)
+		}
+	}
+	return filtered
+}
+
+// LatencyHistogram is a minimal, UI-friendly view of a scraped prometheus
+// histogram, used for the processing latency reported per component.
+// BucketCounts are cumulative, one per upper bound in Bounds.
+type LatencyHistogram struct {
+	Count        uint64
+	Sum          float64
+	Bounds       []float64
+	BucketCounts []uint64
+}
+
+// ComponentMetrics is a per-component snapshot of the internal
+// `otelcol_receiver_*`/`otelcol_processor_*`/`otelcol_exporter_*` metrics
+// collected while the simulation ran, answering "what did this component
+// do to my data" (eg: how many logs a filter processor dropped).
+type ComponentMetrics struct {
+	Component string
+
+	ItemsAccepted int64
+	ItemsRefused  int64
+	ItemsDropped  int64
+	ItemsSent     int64
+	ItemsFailed   int64
+
+	ProcessingLatency *LatencyHistogram
+}
+
+// Puts together a collector service with inmemory receivers and exporters
+// for simulating processing of signal data through a graph of one or more
+// otel collector pipelines, optionally joined together by connectors.
 type CollectorSimulator struct {
 	// collector service to be used for the simulation
 	collectorSvc *service.Service
 
-	// tmp file where collectorSvc will log errors.
-	collectorLogsOutputFilePath string
+	// captures log entries emitted by collectorSvc, in memory, without
+	// losing their level/component structure.
+	observedLogs *observer.ObservedLogs
+
+	// metricsURL is where collectorSvc's internal otelcol_* telemetry can be
+	// scraped on demand, instead of disabling it with telemetry.metrics.level:
+	// none. It points at a prometheus pull exporter bound to an ephemeral
+	// port on loopback, configured the same way a real deployment would wire
+	// one up rather than via a collector-internal extension point.
+	metricsURL string
 
 	// error channel where collector components will report fatal errors
 	// Gets passed in as AsyncErrorChannel in service.Settings when creating a collector service.
 	collectorErrorChannel chan error
 
-	// Unique ids of inmemory receiver and exporter instances that
-	// will be created by collectorSvc
-	inMemoryReceiverId string
-	inMemoryExporterId string
+	// Maps the receiver/exporter ids used in PipelineConfig to the unique
+	// instance ids actually registered with the inmemory receiver/exporter
+	// factories, so repeated simulations don't collide with each other.
+	inMemoryReceiverIds map[string]string
+	inMemoryExporterIds map[string]string
 }
 
 func NewCollectorSimulator(
 	ctx context.Context,
-	signalType component.DataType,
 	processorFactories map[component.Type]processor.Factory,
-	processorConfigs []ProcessorConfig,
-) (simulator *CollectorSimulator, cleanupFn func(), apiErr *model.ApiError) {
+	connectorFactories map[component.Type]connector.Factory,
+	extensionFactories map[component.Type]extension.Factory,
+	pipelineConfigs []PipelineConfig,
+	connectorConfigs []ConnectorConfig,
+	extensionConfigs []ExtensionConfig,
+) (simulator *CollectorSimulator, apiErr *model.ApiError) {
+	connectorIds := map[string]bool{}
+	for _, connectorConf := range connectorConfigs {
+		connectorIds[connectorConf.Id] = true
+	}
+
+	// Every receiver/exporter id referenced by a pipeline that isn't a
+	// connector id gets its own inmemory receiver/exporter instance.
+	inMemoryReceiverIds := map[string]string{}
+	inMemoryExporterIds := map[string]string{}
+	for _, pipelineConf := range pipelineConfigs {
+		for _, receiverId := range pipelineConf.Receivers {
+			if !connectorIds[receiverId] {
+				if _, exists := inMemoryReceiverIds[receiverId]; !exists {
+					inMemoryReceiverIds[receiverId] = uuid.NewString()
+				}
+			}
+		}
+		for _, exporterId := range pipelineConf.Exporters {
+			if !connectorIds[exporterId] {
+				if _, exists := inMemoryExporterIds[exporterId]; !exists {
+					inMemoryExporterIds[exporterId] = uuid.NewString()
+				}
+			}
+		}
+	}
+
 	// Put together collector component factories for use in the simulation
 	receiverFactories, err := receiver.MakeFactoryMap(inmemoryreceiver.NewFactory())
 	if err != nil {
-		return nil, nil, model.InternalError(errors.Wrap(err, "could not create receiver factories."))
+		return nil, model.InternalError(errors.Wrap(err, "could not create receiver factories."))
 	}
 	exporterFactories, err := exporter.MakeFactoryMap(inmemoryexporter.NewFactory())
 	if err != nil {
-		return nil, nil, model.InternalError(errors.Wrap(err, "could not create processor factories."))
+		return nil, model.InternalError(errors.Wrap(err, "could not create processor factories."))
 	}
 	factories := otelcol.Factories{
 		Receivers:  receiverFactories,
 		Processors: processorFactories,
 		Exporters:  exporterFactories,
+		Connectors: connectorFactories,
+		Extensions: extensionFactories,
 	}
 
-	// Prepare collector config yaml for simulation
-	inMemoryReceiverId := uuid.NewString()
-	inMemoryExporterId := uuid.NewString()
-
-	logsOutputFile, err := os.CreateTemp("", "collector-simulator-logs-*")
-	if err != nil {
-		return nil, nil, model.InternalError(errors.Wrap(
-			err, "could not create tmp file for capturing collector logs",
-		))
-	}
-	collectorLogsOutputFilePath := logsOutputFile.Name()
-	cleanupFn = func() {
-		os.Remove(collectorLogsOutputFilePath)
-	}
-	err = logsOutputFile.Close()
+	metricsPort, err := findFreePort()
 	if err != nil {
-		return nil, cleanupFn, model.InternalError(errors.Wrap(err, "could not close tmp collector log file"))
+		return nil, model.InternalError(errors.Wrap(err, "could not reserve a port for simulation metrics"))
 	}
 
 	collectorConfYaml, err := generateSimulationConfig(
-		signalType,
-		inMemoryReceiverId,
-		processorConfigs,
-		inMemoryExporterId,
-		collectorLogsOutputFilePath,
+		pipelineConfigs,
+		connectorConfigs,
+		extensionConfigs,
+		inMemoryReceiverIds,
+		inMemoryExporterIds,
+		metricsPort,
 	)
 	if err != nil {
-		return nil, cleanupFn, model.BadRequest(errors.Wrap(err, "could not generate collector config"))
+		return nil, model.BadRequest(errors.Wrap(err, "could not generate collector config"))
 	}
 
-	// Parse and validate collector config
+	// Parse and validate collector config.
+	//
+	// Providers beyond yaml are registered so processor/extension configs
+	// can reference confmap providers (`file:`, `env:`, `http(s):`) or use
+	// `${env:...}` style expansion, matching how real collector deployments
+	// pull in auth extensions and secrets.
 	yamlP := yamlprovider.New()
 	confProvider, err := otelcol.NewConfigProvider(otelcol.ConfigProviderSettings{
 		ResolverSettings: confmap.ResolverSettings{
-			URIs:      []string{"yaml:" + string(collectorConfYaml)},
-			Providers: map[string]confmap.Provider{yamlP.Scheme(): yamlP},
+			URIs: []string{"yaml:" + string(collectorConfYaml)},
+			Providers: map[string]confmap.Provider{
+				yamlP.Scheme():               yamlP,
+				fileprovider.New().Scheme():  fileprovider.New(),
+				envprovider.New().Scheme():   envprovider.New(),
+				httpprovider.New().Scheme():  httpprovider.New(),
+				httpsprovider.New().Scheme(): httpsprovider.New(),
+			},
 		},
 	})
 	if err != nil {
-		return nil, cleanupFn, model.BadRequest(errors.Wrap(err, "could not create config provider."))
+		return nil, model.BadRequest(errors.Wrap(err, "could not create config provider."))
 	}
 	collectorCfg, err := confProvider.Get(ctx, factories)
 	if err != nil {
-		return nil, cleanupFn, model.BadRequest(errors.Wrap(err, "failed to parse collector config"))
+		return nil, model.BadRequest(errors.Wrap(err, "failed to parse collector config"))
 	}
 
 	if err = collectorCfg.Validate(); err != nil {
-		return nil, cleanupFn, model.BadRequest(errors.Wrap(err, "invalid collector config"))
+		return nil, model.BadRequest(errors.Wrap(err, "invalid collector config"))
 	}
 
+	// Capture collector logs in memory instead of scraping a tmp file, so
+	// entries keep their level/component structure and concurrent
+	// simulations don't race on a shared path.
+	observerCore, observedLogs := observer.New(zapcore.DebugLevel)
+
 	// Build and start collector service.
 	collectorErrChan := make(chan error)
 	svcSettings := service.Settings{
-		Receivers:         receiver.NewBuilder(collectorCfg.Receivers, factories.Receivers),
-		Processors:        processor.NewBuilder(collectorCfg.Processors, factories.Processors),
-		Exporters:         exporter.NewBuilder(collectorCfg.Exporters, factories.Exporters),
-		Connectors:        connector.NewBuilder(collectorCfg.Connectors, factories.Connectors),
-		Extensions:        extension.NewBuilder(collectorCfg.Extensions, factories.Extensions),
+		Receivers:  receiver.NewBuilder(collectorCfg.Receivers, factories.Receivers),
+		Processors: processor.NewBuilder(collectorCfg.Processors, factories.Processors),
+		Exporters:  exporter.NewBuilder(collectorCfg.Exporters, factories.Exporters),
+		Connectors: connector.NewBuilder(collectorCfg.Connectors, factories.Connectors),
+		Extensions: extension.NewBuilder(collectorCfg.Extensions, factories.Extensions),
+		LoggingOptions: []zap.Option{
+			zap.WrapCore(func(zapcore.Core) zapcore.Core { return observerCore }),
+		},
 		AsyncErrorChannel: collectorErrChan,
 	}
 
 	collectorSvc, err := service.New(ctx, svcSettings, collectorCfg.Service)
 	if err != nil {
-		return nil, cleanupFn, model.InternalError(errors.Wrap(err, "could not instantiate collector service"))
+		return nil, model.InternalError(errors.Wrap(err, "could not instantiate collector service"))
 	}
 
 	return &CollectorSimulator{
-		inMemoryReceiverId:          inMemoryReceiverId,
-		inMemoryExporterId:          inMemoryExporterId,
-		collectorSvc:                collectorSvc,
-		collectorErrorChannel:       collectorErrChan,
-		collectorLogsOutputFilePath: collectorLogsOutputFilePath,
-	}, cleanupFn, nil
+		inMemoryReceiverIds:   inMemoryReceiverIds,
+		inMemoryExporterIds:   inMemoryExporterIds,
+		collectorSvc:          collectorSvc,
+		collectorErrorChannel: collectorErrChan,
+		observedLogs:          observedLogs,
+		metricsURL:            fmt.Sprintf("http://127.0.0.1:%d/metrics", metricsPort),
+	}, nil
+}
+
+// findFreePort asks the OS for a loopback TCP port that's free at the time
+// of the call, for binding the simulation's prometheus metrics reader to.
+func findFreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
 func (l *CollectorSimulator) Start(ctx context.Context) (
@@ -149,8 +331,12 @@ func (l *CollectorSimulator) Start(ctx context.Context) (
 	// inmemory components are indexed in a global map after Start is called
 	// on them and will have to be cleaned up to ensure there is no memory leak
 	cleanupFn := func() {
-		inmemoryreceiver.CleanupInstance(l.inMemoryReceiverId)
-		inmemoryexporter.CleanupInstance(l.inMemoryExporterId)
+		for _, instanceId := range l.inMemoryReceiverIds {
+			inmemoryreceiver.CleanupInstance(instanceId)
+		}
+		for _, instanceId := range l.inMemoryExporterIds {
+			inmemoryexporter.CleanupInstance(instanceId)
+		}
 	}
 
 	err := l.collectorSvc.Start(ctx)
@@ -161,16 +347,283 @@ func (l *CollectorSimulator) Start(ctx context.Context) (
 	return cleanupFn, nil
 }
 
-func (l *CollectorSimulator) GetReceiver() *inmemoryreceiver.InMemoryReceiver {
-	return inmemoryreceiver.GetReceiverInstance(l.inMemoryReceiverId)
+// GetReceiverByID returns the inmemory receiver instance backing the
+// receiver id used in a PipelineConfig, or nil if id doesn't refer to
+// an inmemory receiver (eg: it is a connector id).
+func (l *CollectorSimulator) GetReceiverByID(id string) *inmemoryreceiver.InMemoryReceiver {
+	instanceId, exists := l.inMemoryReceiverIds[id]
+	if !exists {
+		return nil
+	}
+	return inmemoryreceiver.GetReceiverInstance(instanceId)
+}
+
+// GetExporterByID returns the inmemory exporter instance backing the
+// exporter id used in a PipelineConfig, or nil if id doesn't refer to
+// an inmemory exporter (eg: it is a connector id).
+func (l *CollectorSimulator) GetExporterByID(id string) *inmemoryexporter.InMemoryExporter {
+	instanceId, exists := l.inMemoryExporterIds[id]
+	if !exists {
+		return nil
+	}
+	return inmemoryexporter.GetExporterInstance(instanceId)
+}
+
+// defaultRunTimeout bounds Run when opts.Timeout isn't set, so a caller
+// driving the simulator from an HTTP handler can never block forever.
+const defaultRunTimeout = 30 * time.Second
+
+// exporterPollInterval is how often Run checks whether input has reached
+// the exporter yet.
+const exporterPollInterval = 50 * time.Millisecond
+
+// requiredStableTicks is how many consecutive polls the exporter's item
+// count must stay unchanged before Run treats the pipeline as settled,
+// short of wantItems (eg: a filter processor dropped some/all of it).
+const requiredStableTicks = 2
+
+// quiescenceTracker detects when a polled count has stopped changing, used
+// by Run to recognize that a pipeline has settled without requiring its
+// exporter to see an exact number of items.
+type quiescenceTracker struct {
+	lastCount   int
+	stableTicks int
+}
+
+func newQuiescenceTracker() *quiescenceTracker {
+	return &quiescenceTracker{lastCount: -1}
 }
 
-func (l *CollectorSimulator) GetExporter() *inmemoryexporter.InMemoryExporter {
-	return inmemoryexporter.GetExporterInstance(l.inMemoryExporterId)
+// observe records the latest polled count and reports whether it has now
+// been stable for requiredStableTicks consecutive calls.
+func (q *quiescenceTracker) observe(currentCount int) bool {
+	if currentCount == q.lastCount {
+		q.stableTicks++
+		return q.stableTicks >= requiredStableTicks
+	}
+	q.lastCount = currentCount
+	q.stableTicks = 0
+	return false
+}
+
+// RunOptions bounds a single Run call.
+type RunOptions struct {
+	// Timeout bounds how long Run waits for input to reach the exporter.
+	// Defaults to defaultRunTimeout if unset.
+	Timeout time.Duration
+
+	// FlushInterval is how often Run polls the exporter while waiting.
+	// Defaults to exporterPollInterval if unset. Run does not flush
+	// processors itself: data a batch processor still holds is only seen
+	// if it is emitted before the exporter's item count has stayed
+	// unchanged for requiredStableTicks polls.
+	FlushInterval time.Duration
+
+	// MaxItems bounds how many items Run reads back off the exporter into
+	// its output (eg: when a fan-out connector delivers more data than was
+	// pushed in). Exported batches are taken whole, so the output can
+	// exceed MaxItems by up to one batch; the exporter's own buffer is not
+	// capped.
+	MaxItems int
+}
+
+// Run pushes input through the receiver identified by receiverId, waits
+// until it has propagated to the exporter identified by exporterId (or an
+// error is reported, or opts.Timeout/ctx fires), collects a pipeline
+// metrics snapshot, and then shuts the simulation down. It exists so the
+// simulator can be driven safely from an HTTP handler without leaking the
+// goroutines collectorSvc.Start spins up, and without making callers work
+// out the GetPipelineMetrics/Shutdown ordering themselves.
+func (l *CollectorSimulator) Run(
+	ctx context.Context,
+	receiverId string,
+	exporterId string,
+	input interface{},
+	opts RunOptions,
+) (
+	output interface{},
+	metrics map[string]*ComponentMetrics,
+	simulationErrs []string,
+	logs []SimulationLogEntry,
+	apiErr *model.ApiError,
+) {
+	cleanupFn, apiErr := l.Start(ctx)
+	if apiErr != nil {
+		return nil, nil, nil, nil, apiErr
+	}
+	defer cleanupFn()
+
+	// inmemory receiver/exporter instances are only indexed once Start has
+	// been called on them, so they are looked up after collectorSvc started.
+	receiverInst := l.GetReceiverByID(receiverId)
+	if receiverInst == nil {
+		l.Shutdown(ctx)
+		return nil, nil, nil, nil, model.BadRequest(fmt.Errorf(
+			"no inmemory receiver registered for id %q", receiverId,
+		))
+	}
+	exporterInst := l.GetExporterByID(exporterId)
+	if exporterInst == nil {
+		l.Shutdown(ctx)
+		return nil, nil, nil, nil, model.BadRequest(fmt.Errorf(
+			"no inmemory exporter registered for id %q", exporterId,
+		))
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultRunTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	wantItems, err := pushSignalData(runCtx, receiverInst, input)
+	if err != nil {
+		l.Shutdown(ctx)
+		return nil, nil, nil, nil, model.BadRequest(errors.Wrap(err, "could not push input into the simulated pipeline"))
+	}
+
+	flushInterval := opts.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = exporterPollInterval
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	// Wait for the exporter to receive input, without requiring it to see
+	// exactly wantItems: processors/connectors can legitimately filter,
+	// sample, transform or reroute data, so a pipeline can settle with
+	// fewer items at the exporter than were pushed in (or none at all, eg:
+	// a filter processor dropping everything). Settling is instead detected
+	// by polling the exporter's item count until it stops changing for
+	// requiredStableTicks consecutive polls, backstopped by the existing
+	// timeout/ctx/error-channel exits.
+	quiescence := newQuiescenceTracker()
+
+waitForExporter:
+	for {
+		currentCount := signalDataItemCount(exporterInst, input)
+		if currentCount >= wantItems || quiescence.observe(currentCount) {
+			break waitForExporter
+		}
+
+		select {
+		case reportedErr := <-l.collectorErrorChannel:
+			simulationErrs = append(simulationErrs, reportedErr.Error())
+			break waitForExporter
+		case <-runCtx.Done():
+			break waitForExporter
+		case <-ticker.C:
+		}
+	}
+
+	output = drainSignalData(exporterInst, input, opts.MaxItems)
+
+	// Collected before Shutdown, since Shutdown tears down the collector's
+	// self-telemetry (and the prometheus reader backing it) along with
+	// everything else.
+	metrics, metricsApiErr := l.GetPipelineMetrics(ctx)
+	if metricsApiErr != nil {
+		simulationErrs = append(simulationErrs, metricsApiErr.Error())
+	}
+
+	shutdownErrs, shutdownLogs, apiErr := l.Shutdown(ctx)
+	simulationErrs = append(simulationErrs, shutdownErrs...)
+	return output, metrics, simulationErrs, shutdownLogs, apiErr
+}
+
+// pushSignalData pushes input (a plog.Logs, pmetric.Metrics or
+// ptrace.Traces) into receiverInst and returns the item count Run should
+// wait to see reach the exporter.
+func pushSignalData(ctx context.Context, receiverInst *inmemoryreceiver.InMemoryReceiver, input interface{}) (int, error) {
+	switch data := input.(type) {
+	case plog.Logs:
+		if err := receiverInst.ConsumeLogs(ctx, data); err != nil {
+			return 0, err
+		}
+		return data.LogRecordCount(), nil
+	case pmetric.Metrics:
+		if err := receiverInst.ConsumeMetrics(ctx, data); err != nil {
+			return 0, err
+		}
+		return data.DataPointCount(), nil
+	case ptrace.Traces:
+		if err := receiverInst.ConsumeTraces(ctx, data); err != nil {
+			return 0, err
+		}
+		return data.SpanCount(), nil
+	default:
+		return 0, fmt.Errorf("unsupported signal data type %T", input)
+	}
+}
+
+// signalDataItemCount returns how many items of input's signal type the
+// exporter has accumulated so far.
+func signalDataItemCount(exporterInst *inmemoryexporter.InMemoryExporter, input interface{}) int {
+	switch input.(type) {
+	case plog.Logs:
+		count := 0
+		for _, ld := range exporterInst.GetLogs() {
+			count += ld.LogRecordCount()
+		}
+		return count
+	case pmetric.Metrics:
+		count := 0
+		for _, md := range exporterInst.GetMetrics() {
+			count += md.DataPointCount()
+		}
+		return count
+	case ptrace.Traces:
+		count := 0
+		for _, td := range exporterInst.GetTraces() {
+			count += td.SpanCount()
+		}
+		return count
+	default:
+		return 0
+	}
+}
+
+// drainSignalData moves the exporter's batches of input's signal type into
+// one output, stopping once it holds at least maxItems (0 meaning unbounded)
+// items. Batches are moved whole, so the output can exceed maxItems by up
+// to one batch.
+func drainSignalData(exporterInst *inmemoryexporter.InMemoryExporter, input interface{}, maxItems int) interface{} {
+	switch input.(type) {
+	case plog.Logs:
+		output := plog.NewLogs()
+		for _, ld := range exporterInst.GetLogs() {
+			ld.ResourceLogs().MoveAndAppendTo(output.ResourceLogs())
+			if maxItems > 0 && output.LogRecordCount() >= maxItems {
+				break
+			}
+		}
+		return output
+	case pmetric.Metrics:
+		output := pmetric.NewMetrics()
+		for _, md := range exporterInst.GetMetrics() {
+			md.ResourceMetrics().MoveAndAppendTo(output.ResourceMetrics())
+			if maxItems > 0 && output.DataPointCount() >= maxItems {
+				break
+			}
+		}
+		return output
+	case ptrace.Traces:
+		output := ptrace.NewTraces()
+		for _, td := range exporterInst.GetTraces() {
+			td.ResourceSpans().MoveAndAppendTo(output.ResourceSpans())
+			if maxItems > 0 && output.SpanCount() >= maxItems {
+				break
+			}
+		}
+		return output
+	default:
+		return nil
+	}
 }
 
 func (l *CollectorSimulator) Shutdown(ctx context.Context) (
-	simulationErrs []string, apiErr *model.ApiError,
+	simulationErrs []string, logs []SimulationLogEntry, apiErr *model.ApiError,
 ) {
 	shutdownErr := l.collectorSvc.Shutdown(ctx)
 
@@ -181,69 +634,266 @@ func (l *CollectorSimulator) Shutdown(ctx context.Context) (
 		simulationErrs = append(simulationErrs, reportedErr.Error())
 	}
 
-	collectorErrorLogs, err := os.ReadFile(l.collectorLogsOutputFilePath)
-	if err != nil {
-		return nil, model.InternalError(fmt.Errorf(
-			"could not read collector logs from tmp file: %w", err,
-		))
-	}
-	if len(collectorErrorLogs) > 0 {
-		errorLines := strings.Split(string(collectorErrorLogs), "\n")
-		simulationErrs = append(simulationErrs, errorLines...)
+	logs = make([]SimulationLogEntry, 0, l.observedLogs.Len())
+	for _, entry := range l.observedLogs.All() {
+		fields := entry.ContextMap()
+		logs = append(logs, SimulationLogEntry{
+			Timestamp: entry.Time,
+			Level:     entry.Level,
+			Component: componentNameFromFields(fields),
+			Message:   entry.Message,
+			Fields:    fields,
+		})
 	}
 
 	if shutdownErr != nil {
-		return simulationErrs, model.InternalError(errors.Wrap(
+		return simulationErrs, logs, model.InternalError(errors.Wrap(
 			shutdownErr, "could not shutdown the collector service",
 		))
 	}
-	return simulationErrs, nil
+	return simulationErrs, logs, nil
+}
+
+// componentAttrKeys are, in order of preference, the resource/metric
+// attribute keys that hold a component's id in the collector's internal
+// otelcol_* telemetry.
+var componentAttrKeys = []string{"processor", "receiver", "exporter", "connector"}
+
+// metricsScrapeTimeout bounds how long GetPipelineMetrics waits for the
+// simulation's prometheus reader to respond.
+const metricsScrapeTimeout = 5 * time.Second
+
+// GetPipelineMetrics returns a snapshot of the collector's internal
+// otelcol_receiver_accepted/refused_*, otelcol_processor_*, and
+// otelcol_exporter_sent/failed_* metrics, one entry per component, by
+// scraping the prometheus reader configured in generateSimulationConfig.
+// Must be called before Shutdown: Shutdown tears down the collector's own
+// self-telemetry along with everything else, which is what owns the
+// prometheus reader's HTTP listener this method scrapes, so calling it
+// afterwards will fail with a connection error instead of a snapshot.
+func (l *CollectorSimulator) GetPipelineMetrics(ctx context.Context) (
+	map[string]*ComponentMetrics, *model.ApiError,
+) {
+	reqCtx, cancel := context.WithTimeout(ctx, metricsScrapeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, l.metricsURL, nil)
+	if err != nil {
+		return nil, model.InternalError(errors.Wrap(err, "could not build simulation metrics scrape request"))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, model.InternalError(errors.Wrap(err, "could not scrape simulation metrics"))
+	}
+	defer resp.Body.Close()
+
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, model.InternalError(errors.Wrap(err, "could not parse simulation metrics"))
+	}
+
+	snapshot := map[string]*ComponentMetrics{}
+	componentMetrics := func(id string) *ComponentMetrics {
+		if existing, exists := snapshot[id]; exists {
+			return existing
+		}
+		created := &ComponentMetrics{Component: id}
+		snapshot[id] = created
+		return created
+	}
+
+	for metricName, family := range families {
+		for _, m := range family.GetMetric() {
+			id, ok := componentIDFromLabels(m.GetLabel())
+			if !ok {
+				continue
+			}
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				addSumToComponentMetrics(componentMetrics(id), metricName, int64(m.GetCounter().GetValue()))
+			case dto.MetricType_HISTOGRAM:
+				if !isLatencyMetric(metricName) {
+					continue
+				}
+				componentMetrics(id).ProcessingLatency = histogramFromProto(m.GetHistogram())
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+// componentIDFromLabels extracts the component id (eg: "filter/my-filter")
+// from a prometheus metric's labels, trying each known component label key.
+func componentIDFromLabels(labels []*dto.LabelPair) (string, bool) {
+	for _, key := range componentAttrKeys {
+		for _, label := range labels {
+			if label.GetName() == key {
+				return label.GetValue(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// isLatencyMetric reports whether metricName is a duration/latency
+// histogram (eg: otelcol_processor_batch_batch_send_size_duration) rather
+// than a count-shaped one (eg: otelcol_processor_batch_batch_send_size),
+// which is also exposed as a histogram but isn't a processing latency.
+func isLatencyMetric(metricName string) bool {
+	return strings.Contains(metricName, "duration") || strings.Contains(metricName, "latency")
+}
+
+// histogramFromProto converts a scraped prometheus histogram into the
+// simulator's minimal LatencyHistogram view.
+func histogramFromProto(h *dto.Histogram) *LatencyHistogram {
+	bounds := make([]float64, 0, len(h.GetBucket()))
+	bucketCounts := make([]uint64, 0, len(h.GetBucket()))
+	for _, bucket := range h.GetBucket() {
+		bounds = append(bounds, bucket.GetUpperBound())
+		bucketCounts = append(bucketCounts, bucket.GetCumulativeCount())
+	}
+	return &LatencyHistogram{
+		Count:        h.GetSampleCount(),
+		Sum:          h.GetSampleSum(),
+		Bounds:       bounds,
+		BucketCounts: bucketCounts,
+	}
+}
+
+// addSumToComponentMetrics attributes a otelcol_*_{accepted,refused,dropped,
+// sent,failed}_* counter's value to the matching ComponentMetrics field
+// based on the metric name.
+func addSumToComponentMetrics(metrics *ComponentMetrics, metricName string, value int64) {
+	switch {
+	case strings.Contains(metricName, "accepted"):
+		metrics.ItemsAccepted += value
+	case strings.Contains(metricName, "refused"):
+		metrics.ItemsRefused += value
+	case strings.Contains(metricName, "dropped"):
+		metrics.ItemsDropped += value
+	case strings.Contains(metricName, "sent"):
+		metrics.ItemsSent += value
+	case strings.Contains(metricName, "failed"):
+		metrics.ItemsFailed += value
+	}
+}
+
+// componentRef returns the fully qualified component id to use for a
+// pipeline-facing receiver/exporter id: the matching connector's component
+// id if id refers to a connector, or the inmemory component id otherwise.
+func componentRef(id string, connectorsByID map[string]ConnectorConfig) string {
+	if connectorConf, isConnector := connectorsByID[id]; isConnector {
+		return fmt.Sprintf("%s/%s", connectorConf.Type, connectorConf.Id)
+	}
+	return fmt.Sprintf("memory/%s", id)
 }
 
 func generateSimulationConfig(
-	signalType component.DataType,
-	receiverId string,
-	processorConfigs []ProcessorConfig,
-	exporterId string,
-	collectorLogsOutputPath string,
+	pipelineConfigs []PipelineConfig,
+	connectorConfigs []ConnectorConfig,
+	extensionConfigs []ExtensionConfig,
+	inMemoryReceiverIds map[string]string,
+	inMemoryExporterIds map[string]string,
+	metricsPort int,
 ) ([]byte, error) {
+	// Logs level is kept at debug since severity-based filtering now happens
+	// on the captured SimulationLogEntry slice (see FilterLogsByMinLevel)
+	// rather than at emission time. Metrics are exposed on a prometheus pull
+	// reader bound to an ephemeral loopback port, so GetPipelineMetrics can
+	// scrape them back the same way a real deployment would, instead of
+	// disabling them altogether.
 	baseConf := fmt.Sprintf(`
-    receivers:
-      memory:
-        id: %s
-    exporters:
-      memory:
-        id: %s
     service:
       telemetry:
         metrics:
-          level: none
+          level: basic
+          readers:
+            - pull:
+                exporter:
+                  prometheus:
+                    host: 127.0.0.1
+                    port: %d
         logs:
-          level: error
-          output_paths: ["%s"]
-    `, receiverId, exporterId, collectorLogsOutputPath)
+          level: debug
+    `, metricsPort)
 
 	simulationConf, err := yaml.Parser().Unmarshal([]byte(baseConf))
 	if err != nil {
 		return nil, err
 	}
 
+	receivers := map[string]interface{}{}
+	for receiverId, instanceId := range inMemoryReceiverIds {
+		receivers[fmt.Sprintf("memory/%s", receiverId)] = map[string]interface{}{"id": instanceId}
+	}
+	simulationConf["receivers"] = receivers
+
+	exporters := map[string]interface{}{}
+	for exporterId, instanceId := range inMemoryExporterIds {
+		exporters[fmt.Sprintf("memory/%s", exporterId)] = map[string]interface{}{"id": instanceId}
+	}
+	simulationConf["exporters"] = exporters
+
+	connectorsByID := map[string]ConnectorConfig{}
+	if len(connectorConfigs) > 0 {
+		connectors := map[string]interface{}{}
+		for _, connectorConf := range connectorConfigs {
+			connectorsByID[connectorConf.Id] = connectorConf
+			connectors[fmt.Sprintf("%s/%s", connectorConf.Type, connectorConf.Id)] = connectorConf.Config
+		}
+		simulationConf["connectors"] = connectors
+	}
+
+	if len(extensionConfigs) > 0 {
+		extensions := map[string]interface{}{}
+		extensionRefs := make([]string, len(extensionConfigs))
+		for i, extensionConf := range extensionConfigs {
+			extensionRef := fmt.Sprintf("%s/%s", extensionConf.Type, extensionConf.Id)
+			extensions[extensionRef] = extensionConf.Config
+			extensionRefs[i] = extensionRef
+		}
+		simulationConf["extensions"] = extensions
+		simulationConf["service"].(map[string]interface{})["extensions"] = extensionRefs
+	}
+
 	processors := map[string]interface{}{}
-	procNamesInOrder := []string{}
-	for _, processorConf := range processorConfigs {
-		processors[processorConf.Name] = processorConf.Config
-		procNamesInOrder = append(procNamesInOrder, processorConf.Name)
+	pipelines := map[string]interface{}{}
+	for _, pipelineConf := range pipelineConfigs {
+		procNamesInOrder := []string{}
+		for _, processorConf := range pipelineConf.Processors {
+			processors[processorConf.Name] = processorConf.Config
+			procNamesInOrder = append(procNamesInOrder, processorConf.Name)
+		}
+
+		receiverRefs := make([]string, len(pipelineConf.Receivers))
+		for i, receiverId := range pipelineConf.Receivers {
+			receiverRefs[i] = componentRef(receiverId, connectorsByID)
+		}
+		exporterRefs := make([]string, len(pipelineConf.Exporters))
+		for i, exporterId := range pipelineConf.Exporters {
+			exporterRefs[i] = componentRef(exporterId, connectorsByID)
+		}
+
+		pipelineId := string(pipelineConf.SignalType)
+		if pipelineConf.Name != "" {
+			pipelineId = fmt.Sprintf("%s/%s", pipelineConf.SignalType, pipelineConf.Name)
+		}
+		if _, exists := pipelines[pipelineId]; exists {
+			return nil, fmt.Errorf(
+				"duplicate pipeline id %q: give pipelines of the same signal type distinct names", pipelineId,
+			)
+		}
+		pipelines[pipelineId] = map[string]interface{}{
+			"receivers":  receiverRefs,
+			"processors": procNamesInOrder,
+			"exporters":  exporterRefs,
+		}
 	}
 	simulationConf["processors"] = processors
 
 	svc := simulationConf["service"].(map[string]interface{})
-	svc["pipelines"] = map[string]interface{}{
-		string(signalType): map[string]interface{}{
-			"receivers":  []string{"memory"},
-			"processors": procNamesInOrder,
-			"exporters":  []string{"memory"},
-		},
-	}
+	svc["pipelines"] = pipelines
 
 	simulationConfYaml, err := yaml.Parser().Marshal(simulationConf)
 	if err != nil {
@@ -0,0 +1,344 @@
+package collectorsimulator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/confmap/provider/httpprovider"
+	"go.opentelemetry.io/collector/confmap/provider/httpsprovider"
+	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/extension"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestComponentRef(t *testing.T) {
+	connectorsByID := map[string]ConnectorConfig{
+		"routed": {Id: "routed", Type: component.Type("routing")},
+	}
+
+	require.Equal(t, "memory/some-receiver", componentRef("some-receiver", connectorsByID))
+	require.Equal(t, "routing/routed", componentRef("routed", connectorsByID))
+}
+
+func TestGenerateSimulationConfig_MultiPipelineWithConnector(t *testing.T) {
+	pipelineConfigs := []PipelineConfig{
+		{
+			Name:       "in",
+			SignalType: component.DataTypeTraces,
+			Receivers:  []string{"traces-in"},
+			Exporters:  []string{"routed"},
+		},
+		{
+			Name:       "out",
+			SignalType: component.DataTypeTraces,
+			Receivers:  []string{"routed"},
+			Exporters:  []string{"traces-out"},
+		},
+	}
+	connectorConfigs := []ConnectorConfig{
+		{Id: "routed", Type: component.Type("routing"), Config: map[string]interface{}{}},
+	}
+	inMemoryReceiverIds := map[string]string{"traces-in": "receiver-instance-id"}
+	inMemoryExporterIds := map[string]string{"traces-out": "exporter-instance-id"}
+
+	confYaml, err := generateSimulationConfig(
+		pipelineConfigs, connectorConfigs, nil, inMemoryReceiverIds, inMemoryExporterIds, 0,
+	)
+	require.NoError(t, err)
+
+	conf, err := yaml.Parser().Unmarshal(confYaml)
+	require.NoError(t, err)
+
+	connectors, ok := conf["connectors"].(map[string]interface{})
+	require.True(t, ok, "expected connectors section, got %#v", conf["connectors"])
+	require.Contains(t, connectors, "routing/routed")
+
+	pipelines, ok := conf["service"].(map[string]interface{})["pipelines"].(map[string]interface{})
+	require.True(t, ok)
+
+	pipelineIn, ok := pipelines["traces/in"].(map[string]interface{})
+	require.True(t, ok, "expected traces/in pipeline, got %#v", pipelines)
+	require.Equal(t, []string{"routing/routed"}, pipelineIn["exporters"])
+
+	pipelineOut, ok := pipelines["traces/out"].(map[string]interface{})
+	require.True(t, ok, "expected traces/out pipeline, got %#v", pipelines)
+	require.Equal(t, []string{"routing/routed"}, pipelineOut["receivers"])
+}
+
+func TestGenerateSimulationConfig_RejectsDuplicatePipelineIds(t *testing.T) {
+	pipelineConfigs := []PipelineConfig{
+		{SignalType: component.DataTypeLogs, Receivers: []string{"logs-in-1"}, Exporters: []string{"logs-out-1"}},
+		{SignalType: component.DataTypeLogs, Receivers: []string{"logs-in-2"}, Exporters: []string{"logs-out-2"}},
+	}
+
+	_, err := generateSimulationConfig(pipelineConfigs, nil, nil, nil, nil, 0)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "duplicate pipeline id")
+}
+
+func TestGenerateSimulationConfig_ExtensionsAndProcessorConfig(t *testing.T) {
+	pipelineConfigs := []PipelineConfig{
+		{
+			SignalType: component.DataTypeLogs,
+			Receivers:  []string{"logs-in"},
+			Processors: []ProcessorConfig{
+				{Name: "filter/drop-debug", Config: map[string]interface{}{"error_mode": "ignore"}},
+			},
+			Exporters: []string{"logs-out"},
+		},
+	}
+	extensionConfigs := []ExtensionConfig{
+		{Id: "basicauth", Type: component.Type("basicauth"), Config: map[string]interface{}{"htpasswd": "${env:AUTH_FILE}"}},
+	}
+	inMemoryReceiverIds := map[string]string{"logs-in": "receiver-instance-id"}
+	inMemoryExporterIds := map[string]string{"logs-out": "exporter-instance-id"}
+
+	confYaml, err := generateSimulationConfig(
+		pipelineConfigs, nil, extensionConfigs, inMemoryReceiverIds, inMemoryExporterIds, 0,
+	)
+	require.NoError(t, err)
+
+	conf, err := yaml.Parser().Unmarshal(confYaml)
+	require.NoError(t, err)
+
+	extensions, ok := conf["extensions"].(map[string]interface{})
+	require.True(t, ok, "expected extensions section, got %#v", conf["extensions"])
+	require.Contains(t, extensions, "basicauth/basicauth")
+
+	svcExtensions, ok := conf["service"].(map[string]interface{})["extensions"].([]string)
+	require.True(t, ok, "expected service.extensions list, got %#v", conf["service"])
+	require.Equal(t, []string{"basicauth/basicauth"}, svcExtensions)
+
+	processors, ok := conf["processors"].(map[string]interface{})
+	require.True(t, ok)
+	require.Contains(t, processors, "filter/drop-debug")
+}
+
+// TestGenerateSimulationConfig_EnvProviderExpandsRefs resolves a generated
+// config through the same confmap providers NewCollectorSimulator registers,
+// proving `${env:...}` refs in an ExtensionConfig.Config actually get
+// expanded rather than just surviving a YAML round-trip unresolved.
+func TestGenerateSimulationConfig_EnvProviderExpandsRefs(t *testing.T) {
+	t.Setenv("COLLECTORSIMULATOR_TEST_AUTH_FILE", "/etc/htpasswd")
+
+	pipelineConfigs := []PipelineConfig{
+		{SignalType: component.DataTypeLogs, Receivers: []string{"logs-in"}, Exporters: []string{"logs-out"}},
+	}
+	extensionConfigs := []ExtensionConfig{
+		{
+			Id:     "basicauth",
+			Type:   component.Type("basicauth"),
+			Config: map[string]interface{}{"htpasswd": "${env:COLLECTORSIMULATOR_TEST_AUTH_FILE}"},
+		},
+	}
+	inMemoryReceiverIds := map[string]string{"logs-in": "receiver-instance-id"}
+	inMemoryExporterIds := map[string]string{"logs-out": "exporter-instance-id"}
+
+	confYaml, err := generateSimulationConfig(
+		pipelineConfigs, nil, extensionConfigs, inMemoryReceiverIds, inMemoryExporterIds, 0,
+	)
+	require.NoError(t, err)
+
+	yamlP := yamlprovider.New()
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"yaml:" + string(confYaml)},
+		Providers: map[string]confmap.Provider{
+			yamlP.Scheme():               yamlP,
+			fileprovider.New().Scheme():  fileprovider.New(),
+			envprovider.New().Scheme():   envprovider.New(),
+			httpprovider.New().Scheme():  httpprovider.New(),
+			httpsprovider.New().Scheme(): httpsprovider.New(),
+		},
+	})
+	require.NoError(t, err)
+
+	resolved, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+
+	var resolvedConf map[string]interface{}
+	require.NoError(t, resolved.Unmarshal(&resolvedConf))
+
+	extensions, ok := resolvedConf["extensions"].(map[string]interface{})
+	require.True(t, ok, "expected extensions section, got %#v", resolvedConf["extensions"])
+	basicauth, ok := extensions["basicauth/basicauth"].(map[string]interface{})
+	require.True(t, ok, "expected basicauth/basicauth extension, got %#v", extensions)
+	require.Equal(t, "/etc/htpasswd", basicauth["htpasswd"])
+}
+
+func TestFilterLogsByMinLevel(t *testing.T) {
+	logs := []SimulationLogEntry{
+		{Message: "debug entry", Level: zapcore.DebugLevel},
+		{Message: "info entry", Level: zapcore.InfoLevel},
+		{Message: "warn entry", Level: zapcore.WarnLevel},
+	}
+
+	filtered := FilterLogsByMinLevel(logs, zapcore.WarnLevel)
+
+	require.Len(t, filtered, 1)
+	require.Equal(t, "warn entry", filtered[0].Message)
+}
+
+func TestComponentNameFromFields(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fields   map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "prefers component over kind and name",
+			fields:   map[string]interface{}{"name": "name-value", "kind": "kind-value", "component": "component-value"},
+			expected: "component-value",
+		},
+		{
+			name:     "falls back to kind when component is absent",
+			fields:   map[string]interface{}{"name": "name-value", "kind": "kind-value"},
+			expected: "kind-value",
+		},
+		{
+			name:     "falls back to name when component and kind are absent",
+			fields:   map[string]interface{}{"name": "name-value"},
+			expected: "name-value",
+		},
+		{
+			name:     "empty when none of the known keys are present",
+			fields:   map[string]interface{}{"other": "value"},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, componentNameFromFields(tc.fields))
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestAddSumToComponentMetrics(t *testing.T) {
+	metrics := &ComponentMetrics{Component: "filter/my-filter"}
+
+	addSumToComponentMetrics(metrics, "otelcol_processor_accepted_log_records", 10)
+	addSumToComponentMetrics(metrics, "otelcol_processor_refused_log_records", 2)
+	addSumToComponentMetrics(metrics, "otelcol_processor_dropped_log_records", 1)
+	addSumToComponentMetrics(metrics, "otelcol_exporter_sent_log_records", 7)
+	addSumToComponentMetrics(metrics, "otelcol_exporter_send_failed_log_records", 3)
+
+	require.Equal(t, int64(10), metrics.ItemsAccepted)
+	require.Equal(t, int64(2), metrics.ItemsRefused)
+	require.Equal(t, int64(1), metrics.ItemsDropped)
+	require.Equal(t, int64(7), metrics.ItemsSent)
+	require.Equal(t, int64(3), metrics.ItemsFailed)
+}
+
+func TestIsLatencyMetric(t *testing.T) {
+	require.True(t, isLatencyMetric("otelcol_processor_batch_process_duration"))
+	require.True(t, isLatencyMetric("otelcol_exporter_request_latency"))
+	require.False(t, isLatencyMetric("otelcol_processor_batch_batch_send_size"))
+}
+
+func TestComponentIDFromLabels(t *testing.T) {
+	labels := []*dto.LabelPair{
+		{Name: strPtr("service_instance_id"), Value: strPtr("abc")},
+		{Name: strPtr("processor"), Value: strPtr("filter/my-filter")},
+	}
+
+	id, ok := componentIDFromLabels(labels)
+	require.True(t, ok)
+	require.Equal(t, "filter/my-filter", id)
+
+	_, ok = componentIDFromLabels([]*dto.LabelPair{{Name: strPtr("service_instance_id"), Value: strPtr("abc")}})
+	require.False(t, ok)
+}
+
+func TestQuiescenceTracker(t *testing.T) {
+	t.Run("settles after requiredStableTicks unchanged polls", func(t *testing.T) {
+		q := newQuiescenceTracker()
+
+		require.False(t, q.observe(3)) // first poll always resets, never settles
+		require.False(t, q.observe(5)) // count is still moving
+		require.False(t, q.observe(5)) // 1st stable tick
+		require.True(t, q.observe(5))  // 2nd stable tick: settled short of any target count
+	})
+
+	t.Run("resets on any change, however small", func(t *testing.T) {
+		q := newQuiescenceTracker()
+
+		require.False(t, q.observe(0))
+		require.False(t, q.observe(0))
+		require.False(t, q.observe(1)) // changed again right before settling: resets
+		require.False(t, q.observe(1))
+		require.True(t, q.observe(1))
+	})
+
+	t.Run("settles at a stable zero count, eg: everything filtered out", func(t *testing.T) {
+		q := newQuiescenceTracker()
+
+		require.False(t, q.observe(0))
+		require.False(t, q.observe(0))
+		require.True(t, q.observe(0))
+	})
+}
+
+// TestCollectorSimulator_RunEndToEnd builds a real CollectorSimulator with a
+// single logs pipeline (no connectors/extensions/processors needed), starts
+// it, and drives a record through Run against the live collector.Service.
+// This is the one true end-to-end case for the package: it's what would have
+// caught the metrics-scraped-after-shutdown ordering bug, since
+// l.metricsURL's listener really is torn down by a live Shutdown, and it
+// proves zap.WrapCore genuinely captures log entries emitted by a running
+// collector rather than just compiling.
+func TestCollectorSimulator_RunEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	pipelineConfigs := []PipelineConfig{
+		{
+			SignalType: component.DataTypeLogs,
+			Receivers:  []string{"logs-in"},
+			Exporters:  []string{"logs-out"},
+		},
+	}
+
+	simulator, apiErr := NewCollectorSimulator(
+		ctx,
+		map[component.Type]processor.Factory{},
+		map[component.Type]connector.Factory{},
+		map[component.Type]extension.Factory{},
+		pipelineConfigs,
+		nil,
+		nil,
+	)
+	require.Nil(t, apiErr, "expected a simulator, got %v", apiErr)
+
+	input := plog.NewLogs()
+	record := input.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.Body().SetStr("hello from the simulator")
+
+	output, metrics, simulationErrs, logs, apiErr := simulator.Run(
+		ctx, "logs-in", "logs-out", input, RunOptions{Timeout: 5 * time.Second},
+	)
+	require.Nil(t, apiErr, "expected Run to succeed, got %v", apiErr)
+	require.Empty(t, simulationErrs)
+
+	outputLogs, ok := output.(plog.Logs)
+	require.True(t, ok, "expected plog.Logs output, got %T", output)
+	require.Equal(t, 1, outputLogs.LogRecordCount(), "expected the pushed record to reach the exporter")
+
+	require.NotEmpty(t, logs, "expected the live collector's own logs to be captured via zap.WrapCore")
+
+	require.NotNil(t, metrics, "expected a metrics snapshot scraped before Shutdown tore down self-telemetry")
+}